@@ -0,0 +1,121 @@
+package cannon
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"golang.org/x/sync/singleflight"
+)
+
+// defaultProofCacheSize bounds the in-memory proofData cache a CannonTraceProvider keeps when
+// WithCacheSize is not given a positive size.
+const defaultProofCacheSize = 4096
+
+// CachingMetricer receives instrumentation about a CannonTraceProvider's in-memory proof cache
+// and the generator calls it coordinates.
+type CachingMetricer interface {
+	RecordCannonProofCacheHit()
+	RecordCannonProofCacheMiss()
+	RecordCannonProofGenerationTime(d time.Duration)
+	RecordCannonProofsInFlight(n int)
+}
+
+// NoopCachingMetrics discards all cache and generation instrumentation. It's the default so
+// callers that don't care about these metrics don't have to provide an implementation.
+type NoopCachingMetrics struct{}
+
+func (NoopCachingMetrics) RecordCannonProofCacheHit()                      {}
+func (NoopCachingMetrics) RecordCannonProofCacheMiss()                     {}
+func (NoopCachingMetrics) RecordCannonProofGenerationTime(_ time.Duration) {}
+func (NoopCachingMetrics) RecordCannonProofsInFlight(_ int)                {}
+
+// proofCache coalesces concurrent generator invocations for the same step into a single call and
+// keeps a bounded LRU of recently loaded proofs (plus the final state) in memory, so that Get,
+// GetOracleData and GetPreimage for the same step touch the ProofStore at most once.
+type proofCache struct {
+	metrics CachingMetricer
+
+	proofs *lru.Cache[uint64, *proofData]
+	group  singleflight.Group
+
+	finalStateMu sync.Mutex
+	finalState   *mipsevm.State
+	haveFinal    bool
+	finalGroup   singleflight.Group
+
+	inFlight int64
+}
+
+func newProofCache(metrics CachingMetricer, size int) *proofCache {
+	if size <= 0 {
+		size = defaultProofCacheSize
+	}
+	proofs, _ := lru.New[uint64, *proofData](size)
+	return &proofCache{metrics: metrics, proofs: proofs}
+}
+
+// getProof returns the cached proof for step, calling load to populate it on a cache miss.
+// Concurrent calls for the same step coalesce into a single call to load.
+func (c *proofCache) getProof(step uint64, load func() (*proofData, error)) (*proofData, error) {
+	if proof, ok := c.proofs.Get(step); ok {
+		c.metrics.RecordCannonProofCacheHit()
+		return proof, nil
+	}
+	c.metrics.RecordCannonProofCacheMiss()
+	key := fmt.Sprintf("%d", step)
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		n := atomic.AddInt64(&c.inFlight, 1)
+		c.metrics.RecordCannonProofsInFlight(int(n))
+		defer func() {
+			n := atomic.AddInt64(&c.inFlight, -1)
+			c.metrics.RecordCannonProofsInFlight(int(n))
+		}()
+		start := time.Now()
+		proof, err := load()
+		c.metrics.RecordCannonProofGenerationTime(time.Since(start))
+		if err == nil {
+			// Populate the cache before the flight ends so a caller arriving just after it sees a hit.
+			c.proofs.Add(step, proof)
+		}
+		return proof, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	proof, _ := v.(*proofData)
+	return proof, nil
+}
+
+// getFinalState returns the cached final state, calling load to populate it on a cache miss. This
+// is what keeps repeated ProofAfterEndOfTrace lookups from re-reading the final state on every
+// call once the trace has completed.
+func (c *proofCache) getFinalState(load func() (*mipsevm.State, error)) (*mipsevm.State, error) {
+	c.finalStateMu.Lock()
+	if c.haveFinal {
+		state := c.finalState
+		c.finalStateMu.Unlock()
+		c.metrics.RecordCannonProofCacheHit()
+		return state, nil
+	}
+	c.finalStateMu.Unlock()
+	c.metrics.RecordCannonProofCacheMiss()
+	v, err, _ := c.finalGroup.Do("final-state", func() (interface{}, error) {
+		state, err := load()
+		if err != nil {
+			return nil, err
+		}
+		c.finalStateMu.Lock()
+		c.finalState = state
+		c.haveFinal = true
+		c.finalStateMu.Unlock()
+		return state, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*mipsevm.State), nil
+}
@@ -0,0 +1,239 @@
+package cannon
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// ProofStoreFlagName is the CLI flag used to select the backend a CannonTraceProvider persists
+// generated proofs to. Supported values are "disk" (default), "leveldb" and "memory".
+const ProofStoreFlagName = "cannon-proof-store"
+
+// ProofStoreKind identifies a ProofStore implementation, as selected via --cannon-proof-store.
+type ProofStoreKind string
+
+const (
+	ProofStoreDisk    ProofStoreKind = "disk"
+	ProofStoreLevelDB ProofStoreKind = "leveldb"
+	ProofStoreMemory  ProofStoreKind = "memory"
+)
+
+// ProofStore persists the step proofs and final state generated while executing a cannon trace.
+// A full dispute trace can produce millions of tiny proof files, which is pathological on most
+// filesystems, so this is pluggable: the default disk layout is fine for local/single-game use,
+// while LevelDBProofStore keeps all proofs in a single database instead of one file per step.
+type ProofStore interface {
+	// LoadProof returns the proof for step, or nil if it has not been generated yet.
+	LoadProof(step uint64) (*proofData, error)
+	// PutProof persists the proof generated for step.
+	PutProof(step uint64, p *proofData) error
+	// LoadFinalState returns the state the trace ended on, if the trace has completed.
+	LoadFinalState() (*mipsevm.State, bool, error)
+	// PutFinalState persists the state the trace ended on.
+	PutFinalState(state *mipsevm.State) error
+}
+
+// NewProofStore creates the ProofStore selected by kind, rooted at dir.
+func NewProofStore(kind ProofStoreKind, dir string) (ProofStore, error) {
+	switch kind {
+	case "", ProofStoreDisk:
+		return NewDiskProofStore(dir), nil
+	case ProofStoreLevelDB:
+		return NewLevelDBProofStore(filepath.Join(dir, "proofs-db"))
+	case ProofStoreMemory:
+		return NewMemProofStore(), nil
+	default:
+		return nil, fmt.Errorf("invalid %v: %q", ProofStoreFlagName, kind)
+	}
+}
+
+// DiskProofStore is the original flat-file layout: proofsDir/<step>.json plus a finalState file,
+// both rooted at dir.
+type DiskProofStore struct {
+	dir string
+}
+
+func NewDiskProofStore(dir string) *DiskProofStore {
+	return &DiskProofStore{dir: dir}
+}
+
+func (s *DiskProofStore) proofPath(step uint64) string {
+	return filepath.Join(s.dir, proofsDir, fmt.Sprintf("%d.json", step))
+}
+
+func (s *DiskProofStore) LoadProof(step uint64) (*proofData, error) {
+	file, err := os.Open(s.proofPath(step))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot open proof file (%v): %w", s.proofPath(step), err)
+	}
+	defer file.Close()
+	var proof proofData
+	if err := json.NewDecoder(file).Decode(&proof); err != nil {
+		return nil, fmt.Errorf("failed to read proof (%v): %w", s.proofPath(step), err)
+	}
+	return &proof, nil
+}
+
+func (s *DiskProofStore) PutProof(step uint64, p *proofData) error {
+	if err := os.MkdirAll(filepath.Join(s.dir, proofsDir), 0o755); err != nil {
+		return fmt.Errorf("create proofs dir: %w", err)
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal proof for step %v: %w", step, err)
+	}
+	return os.WriteFile(s.proofPath(step), data, 0o644)
+}
+
+func (s *DiskProofStore) LoadFinalState() (*mipsevm.State, bool, error) {
+	path := filepath.Join(s.dir, finalState)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("cannot read final state (%v): %w", path, err)
+	}
+	var state mipsevm.State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, false, fmt.Errorf("invalid final state (%v): %w", path, err)
+	}
+	return &state, true, nil
+}
+
+func (s *DiskProofStore) PutFinalState(state *mipsevm.State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshal final state: %w", err)
+	}
+	return os.WriteFile(filepath.Join(s.dir, finalState), data, 0o644)
+}
+
+// MemProofStore is an in-memory ProofStore, intended to replace the t.TempDir()+os.WriteFile
+// boilerplate tests previously used to stage proof fixtures.
+type MemProofStore struct {
+	mu         sync.Mutex
+	proofs     map[uint64]*proofData
+	finalState *mipsevm.State
+}
+
+func NewMemProofStore() *MemProofStore {
+	return &MemProofStore{proofs: make(map[uint64]*proofData)}
+}
+
+func (s *MemProofStore) LoadProof(step uint64) (*proofData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.proofs[step], nil
+}
+
+func (s *MemProofStore) PutProof(step uint64, p *proofData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.proofs[step] = p
+	return nil
+}
+
+func (s *MemProofStore) LoadFinalState() (*mipsevm.State, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finalState == nil {
+		return nil, false, nil
+	}
+	return s.finalState, true, nil
+}
+
+func (s *MemProofStore) PutFinalState(state *mipsevm.State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finalState = state
+	return nil
+}
+
+var (
+	levelDBProofKeyPrefix = []byte("proof-")
+	levelDBFinalStateKey  = []byte("final-state")
+)
+
+func levelDBProofKey(step uint64) []byte {
+	key := make([]byte, len(levelDBProofKeyPrefix)+8)
+	copy(key, levelDBProofKeyPrefix)
+	binary.BigEndian.PutUint64(key[len(levelDBProofKeyPrefix):], step)
+	return key
+}
+
+// LevelDBProofStore is a KV-backed ProofStore. Unlike DiskProofStore it avoids creating one file
+// per trace step: each proof is a separate key in one LevelDB database. Each PutProof is its own
+// write; writes are not batched and the store offers no iteration over stored proofs.
+type LevelDBProofStore struct {
+	db *leveldb.DB
+}
+
+func NewLevelDBProofStore(dir string) (*LevelDBProofStore, error) {
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open leveldb proof store at %v: %w", dir, err)
+	}
+	return &LevelDBProofStore{db: db}, nil
+}
+
+func (s *LevelDBProofStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *LevelDBProofStore) LoadProof(step uint64) (*proofData, error) {
+	data, err := s.db.Get(levelDBProofKey(step), nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load proof for step %v: %w", step, err)
+	}
+	var proof proofData
+	if err := json.Unmarshal(data, &proof); err != nil {
+		return nil, fmt.Errorf("decode proof for step %v: %w", step, err)
+	}
+	return &proof, nil
+}
+
+func (s *LevelDBProofStore) PutProof(step uint64, p *proofData) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal proof for step %v: %w", step, err)
+	}
+	return s.db.Put(levelDBProofKey(step), data, nil)
+}
+
+func (s *LevelDBProofStore) LoadFinalState() (*mipsevm.State, bool, error) {
+	data, err := s.db.Get(levelDBFinalStateKey, nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("load final state: %w", err)
+	}
+	var state mipsevm.State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, false, fmt.Errorf("decode final state: %w", err)
+	}
+	return &state, true, nil
+}
+
+func (s *LevelDBProofStore) PutFinalState(state *mipsevm.State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshal final state: %w", err)
+	}
+	return s.db.Put(levelDBFinalStateKey, data, nil)
+}
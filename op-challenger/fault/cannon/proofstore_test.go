@@ -0,0 +1,124 @@
+package cannon
+
+import (
+	"io"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// storeFactories lists all ProofStore implementations so the contract tests below run against
+// every one of them instead of just MemProofStore.
+func storeFactories(t *testing.T) map[string]func(t *testing.T) ProofStore {
+	return map[string]func(t *testing.T) ProofStore{
+		"Disk": func(t *testing.T) ProofStore {
+			return NewDiskProofStore(t.TempDir())
+		},
+		"Memory": func(t *testing.T) ProofStore {
+			return NewMemProofStore()
+		},
+		"LevelDB": func(t *testing.T) ProofStore {
+			store, err := NewLevelDBProofStore(t.TempDir())
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = store.Close() })
+			return store
+		},
+	}
+}
+
+func TestProofStore_LoadProofMissing(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			proof, err := store.LoadProof(1234)
+			require.NoError(t, err)
+			require.Nil(t, proof)
+		})
+	}
+}
+
+func TestProofStore_PutThenLoadProofRoundTrips(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			expected := &proofData{
+				ClaimValue:   common.Hash{0xaa}.Bytes(),
+				StateData:    []byte{0xbb},
+				ProofData:    []byte{0xcc},
+				OracleKey:    common.Hash{0xdd}.Bytes(),
+				OracleValue:  []byte{0xee},
+				OracleOffset: 7,
+			}
+			require.NoError(t, store.PutProof(42, expected))
+
+			actual, err := store.LoadProof(42)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+
+			// A different step must not be affected.
+			other, err := store.LoadProof(43)
+			require.NoError(t, err)
+			require.Nil(t, other)
+		})
+	}
+}
+
+func TestProofStore_LoadFinalStateMissing(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			state, ok, err := store.LoadFinalState()
+			require.NoError(t, err)
+			require.False(t, ok)
+			require.Nil(t, state)
+		})
+	}
+}
+
+func TestProofStore_PutThenLoadFinalStateRoundTrips(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			expected := &mipsevm.State{
+				Memory: mipsevm.NewMemory(),
+				Step:   99,
+				Exited: true,
+			}
+			require.NoError(t, store.PutFinalState(expected))
+
+			actual, ok, err := store.LoadFinalState()
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestNewProofStore(t *testing.T) {
+	tests := []struct {
+		kind    ProofStoreKind
+		wantErr bool
+	}{
+		{kind: ProofStoreDisk},
+		{kind: ""},
+		{kind: ProofStoreMemory},
+		{kind: ProofStoreLevelDB},
+		{kind: "bogus", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(string(test.kind), func(t *testing.T) {
+			store, err := NewProofStore(test.kind, t.TempDir())
+			if closer, ok := store.(io.Closer); ok {
+				t.Cleanup(func() { _ = closer.Close() })
+			}
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, store)
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package cannon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	proofsDir  = "proofs"
+	finalState = "final.json"
+)
+
+// ErrProofNotAvailable is returned when a proof is requested that does not already exist in the
+// ProofStore and the CannonTraceProvider is configured to never invoke the generator.
+var ErrProofNotAvailable = errors.New("proof not available")
+
+type proofData struct {
+	ClaimValue   []byte `json:"post"`
+	StateData    []byte `json:"state-data"`
+	ProofData    []byte `json:"proof-data"`
+	OracleKey    []byte `json:"oracle-key,omitempty"`
+	OracleValue  []byte `json:"oracle-value,omitempty"`
+	OracleOffset uint32 `json:"oracle-offset,omitempty"`
+}
+
+// PreimageOracleData holds the preimage key/value pair required to answer an oracle read
+// that occurred while executing a single step of the cannon trace.
+type PreimageOracleData struct {
+	IsLocal      bool
+	OracleKey    []byte
+	OracleData   []byte
+	OracleOffset uint32
+}
+
+// ProofGenerator runs the cannon MIPS emulator up to (and including) step i and writes the
+// resulting proof and state data to dir.
+type ProofGenerator interface {
+	GenerateProof(ctx context.Context, dir string, i uint64) error
+}
+
+// CannonTraceProvider reads state proofs and preimage data from its ProofStore, generating any
+// missing proofs on demand by running the cannon executable via generator.
+type CannonTraceProvider struct {
+	logger    log.Logger
+	dir       string
+	prestate  string
+	generator ProofGenerator
+	store     ProofStore
+	readOnly  bool
+	metrics   CachingMetricer
+	cacheSize int
+	cache     *proofCache
+}
+
+// TraceProviderOpt configures optional behavior of a CannonTraceProvider.
+type TraceProviderOpt func(provider *CannonTraceProvider)
+
+// WithReadOnly puts the provider into read-only mode: it will only ever serve proofs that
+// already exist in the ProofStore and returns ErrProofNotAvailable rather than invoking the generator.
+// This is intended for verifier/monitor processes that consume a proofs directory populated by a
+// separate builder process, without risking an accidental (and expensive) MIPS emulator run.
+func WithReadOnly(readOnly bool) TraceProviderOpt {
+	return func(provider *CannonTraceProvider) {
+		provider.readOnly = readOnly
+	}
+}
+
+// WithCacheSize bounds the number of decoded proofs the provider keeps in memory. Defaults to
+// defaultProofCacheSize.
+func WithCacheSize(size int) TraceProviderOpt {
+	return func(provider *CannonTraceProvider) {
+		provider.cacheSize = size
+	}
+}
+
+// WithMetrics reports cache hits/misses, in-flight generation counts and generation latency to m
+// instead of discarding them.
+func WithMetrics(m CachingMetricer) TraceProviderOpt {
+	return func(provider *CannonTraceProvider) {
+		provider.metrics = m
+	}
+}
+
+func NewTraceProvider(logger log.Logger, dir string, prestate string, generator ProofGenerator, store ProofStore, opts ...TraceProviderOpt) *CannonTraceProvider {
+	provider := &CannonTraceProvider{
+		logger:    logger,
+		dir:       dir,
+		prestate:  prestate,
+		generator: generator,
+		store:     store,
+		metrics:   NoopCachingMetrics{},
+	}
+	for _, opt := range opts {
+		opt(provider)
+	}
+	provider.cache = newProofCache(provider.metrics, provider.cacheSize)
+	return provider
+}
+
+func (p *CannonTraceProvider) Get(ctx context.Context, i uint64) (common.Hash, error) {
+	proof, err := p.loadProof(ctx, i)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if proof == nil {
+		state, err := p.loadFinalState()
+		if err != nil {
+			return common.Hash{}, err
+		}
+		return crypto.Keccak256Hash(state.EncodeWitness()), nil
+	}
+	if len(proof.ClaimValue) == 0 {
+		return common.Hash{}, fmt.Errorf("proof at step %v missing post hash", i)
+	}
+	return common.BytesToHash(proof.ClaimValue), nil
+}
+
+func (p *CannonTraceProvider) GetOracleData(ctx context.Context, i uint64) (*PreimageOracleData, error) {
+	proof, err := p.loadProof(ctx, i)
+	if err != nil {
+		return nil, err
+	}
+	if proof == nil {
+		proof, err = p.loadPriorPreimageProof(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	data := &PreimageOracleData{
+		IsLocal:      len(proof.OracleKey) == 0,
+		OracleKey:    proof.OracleKey,
+		OracleData:   proof.OracleValue,
+		OracleOffset: proof.OracleOffset,
+	}
+	return data, nil
+}
+
+func (p *CannonTraceProvider) GetPreimage(ctx context.Context, i uint64) ([]byte, []byte, error) {
+	proof, err := p.loadProof(ctx, i)
+	if err != nil {
+		return nil, nil, err
+	}
+	if proof == nil {
+		proof, err = p.loadPriorPreimageProof(ctx, i)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(proof.StateData) == 0 {
+		return nil, nil, fmt.Errorf("proof at step %v missing state data", i)
+	}
+	return proof.StateData, proof.ProofData, nil
+}
+
+// Close releases any resources held by the configured ProofStore, such as an open LevelDB handle
+// and its directory lock file. Callers that select a ProofStore requiring cleanup (currently only
+// LevelDBProofStore) must call this once they're done with the provider.
+func (p *CannonTraceProvider) Close() error {
+	if closer, ok := p.store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (p *CannonTraceProvider) AbsolutePreState(ctx context.Context) ([]byte, error) {
+	path := filepath.Join(p.dir, p.prestate)
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read prestate at %v: %w", path, err)
+	}
+	var state mipsevm.State
+	if err := json.Unmarshal(file, &state); err != nil {
+		return nil, fmt.Errorf("invalid mipsevm state (%v): %w", path, err)
+	}
+	return state.EncodeWitness(), nil
+}
+
+// loadProof returns the proof for step i, generating it first if it doesn't yet exist in the
+// store. A nil proof (with a nil error) indicates that step i is past the end of the recorded
+// trace and the final state should be consulted instead. Concurrent calls for the same step
+// coalesce into a single generator invocation and a successful result is cached in memory, so
+// that Get, GetOracleData and GetPreimage for the same step touch the store at most once.
+func (p *CannonTraceProvider) loadProof(ctx context.Context, i uint64) (*proofData, error) {
+	return p.cache.getProof(i, func() (*proofData, error) {
+		return p.loadProofUncached(ctx, i)
+	})
+}
+
+func (p *CannonTraceProvider) loadProofUncached(ctx context.Context, i uint64) (*proofData, error) {
+	proof, err := p.store.LoadProof(i)
+	if err != nil {
+		return nil, fmt.Errorf("load proof for step %v: %w", i, err)
+	}
+	if proof != nil {
+		return proof, nil
+	}
+	if p.readOnly {
+		// store.LoadProof returns a nil proof both when step i hasn't been generated yet and when i
+		// is past the end of the trace, which is exactly how the dispute game asks for the claim at
+		// the max game depth. Consult the final state (without invoking the generator) before giving
+		// up, so a read-only provider can still answer that query once a separate builder process has
+		// already populated it.
+		state, err := p.loadFinalState()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, ErrProofNotAvailable
+			}
+			return nil, err
+		}
+		if i < state.Step {
+			// Step i is within the trace: its proof just hasn't been generated yet.
+			return nil, ErrProofNotAvailable
+		}
+		return nil, nil
+	}
+	if err := p.generator.GenerateProof(ctx, p.dir, i); err != nil {
+		return nil, fmt.Errorf("generate proof at step %v: %w", i, err)
+	}
+	return p.ingestGeneratedProof(i)
+}
+
+// ingestGeneratedProof reads the flat proof file the generator just wrote to its scratch
+// directory, persists it in the configured ProofStore, and removes the scratch file. A nil proof
+// (with a nil error) indicates the generator didn't write a proof for step i because it's past
+// the end of the recorded trace.
+func (p *CannonTraceProvider) ingestGeneratedProof(i uint64) (*proofData, error) {
+	path := filepath.Join(p.dir, proofsDir, fmt.Sprintf("%d.json", i))
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot open generated proof file (%v): %w", path, err)
+	}
+	var proof proofData
+	decodeErr := json.NewDecoder(file).Decode(&proof)
+	file.Close()
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to read generated proof (%v): %w", path, decodeErr)
+	}
+	if err := p.store.PutProof(i, &proof); err != nil {
+		return nil, fmt.Errorf("store proof for step %v: %w", i, err)
+	}
+	p.cleanupScratchFile(path)
+	return &proof, nil
+}
+
+// cleanupScratchFile removes a flat file the generator wrote to its scratch directory once its
+// contents have been ingested into the configured ProofStore. Without this, picking a KV-backed
+// ProofStore wouldn't avoid the flat-file explosion it's meant to fix: it would just add a second,
+// duplicate copy of every proof on top of the unchanged pile of scratch files. DiskProofStore reads
+// straight out of that same scratch directory, so for it this is a no-op: removing the file would
+// just mean regenerating it on the next lookup.
+func (p *CannonTraceProvider) cleanupScratchFile(path string) {
+	if _, isDiskStore := p.store.(*DiskProofStore); isDiskStore {
+		return
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		p.logger.Warn("failed to remove scratch proof file after ingesting into proof store", "path", path, "err", err)
+	}
+}
+
+// loadPriorPreimageProof loads the proof for the final real step of the trace, which is the step
+// that produced whatever preimage read caused the program to exit. It's used to answer
+// GetOracleData/GetPreimage queries for steps beyond the end of the trace.
+func (p *CannonTraceProvider) loadPriorPreimageProof(ctx context.Context, i uint64) (*proofData, error) {
+	state, err := p.loadFinalState()
+	if err != nil {
+		return nil, err
+	}
+	if state.Step > i {
+		return nil, fmt.Errorf("no proof available for step %v", i)
+	}
+	proof, err := p.loadProof(ctx, state.Step-1)
+	if err != nil {
+		return nil, err
+	}
+	if proof == nil {
+		return nil, fmt.Errorf("no proof available for final step %v", state.Step-1)
+	}
+	return proof, nil
+}
+
+// loadFinalState returns the state the trace ended on. It never invokes the generator itself: it
+// just ingests whatever final state file the generator (or a separate builder process) already
+// left in the store or its scratch directory. That makes it safe to call both after loadProof has
+// tried (and failed) to generate a missing proof, and directly from a read-only provider that must
+// never invoke the generator at all. The result is cached in memory so that repeated
+// ProofAfterEndOfTrace lookups don't repeatedly re-read it from the store.
+func (p *CannonTraceProvider) loadFinalState() (*mipsevm.State, error) {
+	return p.cache.getFinalState(p.loadFinalStateUncached)
+}
+
+func (p *CannonTraceProvider) loadFinalStateUncached() (*mipsevm.State, error) {
+	state, ok, err := p.store.LoadFinalState()
+	if err != nil {
+		return nil, fmt.Errorf("load final state: %w", err)
+	}
+	if ok {
+		return state, nil
+	}
+	path := filepath.Join(p.dir, finalState)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read final state (%v): %w", path, err)
+	}
+	var loaded mipsevm.State
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return nil, fmt.Errorf("invalid final state (%v): %w", path, err)
+	}
+	if err := p.store.PutFinalState(&loaded); err != nil {
+		return nil, fmt.Errorf("store final state: %w", err)
+	}
+	p.cleanupScratchFile(path)
+	return &loaded, nil
+}
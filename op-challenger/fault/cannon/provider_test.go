@@ -8,7 +8,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
 	"github.com/ethereum-optimism/optimism/op-node/testlog"
@@ -22,9 +26,9 @@ import (
 var testData embed.FS
 
 func TestGet(t *testing.T) {
-	dataDir, prestate := setupTestData(t)
+	dataDir, store, prestate := setupTestData(t)
 	t.Run("ExistingProof", func(t *testing.T) {
-		provider, generator := setupWithTestData(t, dataDir, prestate)
+		provider, generator := setupWithTestData(t, dataDir, store, prestate)
 		value, err := provider.Get(context.Background(), 0)
 		require.NoError(t, err)
 		require.Equal(t, common.HexToHash("0x45fd9aa59768331c726e719e76aa343e73123af888804604785ae19506e65e87"), value)
@@ -32,7 +36,7 @@ func TestGet(t *testing.T) {
 	})
 
 	t.Run("ProofAfterEndOfTrace", func(t *testing.T) {
-		provider, generator := setupWithTestData(t, dataDir, prestate)
+		provider, generator := setupWithTestData(t, dataDir, store, prestate)
 		generator.finalState = &mipsevm.State{
 			Memory: &mipsevm.Memory{},
 			Step:   10,
@@ -45,14 +49,14 @@ func TestGet(t *testing.T) {
 	})
 
 	t.Run("MissingPostHash", func(t *testing.T) {
-		provider, generator := setupWithTestData(t, dataDir, prestate)
+		provider, generator := setupWithTestData(t, dataDir, store, prestate)
 		_, err := provider.Get(context.Background(), 1)
 		require.ErrorContains(t, err, "missing post hash")
 		require.Empty(t, generator.generated)
 	})
 
 	t.Run("IgnoreUnknownFields", func(t *testing.T) {
-		provider, generator := setupWithTestData(t, dataDir, prestate)
+		provider, generator := setupWithTestData(t, dataDir, store, prestate)
 		value, err := provider.Get(context.Background(), 2)
 		require.NoError(t, err)
 		expected := common.HexToHash("bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
@@ -62,9 +66,9 @@ func TestGet(t *testing.T) {
 }
 
 func TestGetOracleData(t *testing.T) {
-	dataDir, prestate := setupTestData(t)
+	dataDir, store, prestate := setupTestData(t)
 	t.Run("ExistingProof", func(t *testing.T) {
-		provider, generator := setupWithTestData(t, dataDir, prestate)
+		provider, generator := setupWithTestData(t, dataDir, store, prestate)
 		oracleData, err := provider.GetOracleData(context.Background(), 420)
 		require.NoError(t, err)
 		require.False(t, oracleData.IsLocal)
@@ -76,7 +80,7 @@ func TestGetOracleData(t *testing.T) {
 	})
 
 	t.Run("ProofAfterEndOfTrace", func(t *testing.T) {
-		provider, generator := setupWithTestData(t, dataDir, prestate)
+		provider, generator := setupWithTestData(t, dataDir, store, prestate)
 		generator.finalState = &mipsevm.State{
 			Memory: &mipsevm.Memory{},
 			Step:   10,
@@ -100,7 +104,7 @@ func TestGetOracleData(t *testing.T) {
 	})
 
 	t.Run("IgnoreUnknownFields", func(t *testing.T) {
-		provider, generator := setupWithTestData(t, dataDir, prestate)
+		provider, generator := setupWithTestData(t, dataDir, store, prestate)
 		oracleData, err := provider.GetOracleData(context.Background(), 421)
 		require.NoError(t, err)
 		require.False(t, oracleData.IsLocal)
@@ -113,9 +117,9 @@ func TestGetOracleData(t *testing.T) {
 }
 
 func TestGetPreimage(t *testing.T) {
-	dataDir, prestate := setupTestData(t)
+	dataDir, store, prestate := setupTestData(t)
 	t.Run("ExistingProof", func(t *testing.T) {
-		provider, generator := setupWithTestData(t, dataDir, prestate)
+		provider, generator := setupWithTestData(t, dataDir, store, prestate)
 		value, proof, err := provider.GetPreimage(context.Background(), 0)
 		require.NoError(t, err)
 		expected := common.Hex2Bytes("b8f068de604c85ea0e2acd437cdb47add074a2d70b81d018390c504b71fe26f400000000000000000000000000000000000000000000000000000000000000000000000000")
@@ -126,7 +130,7 @@ func TestGetPreimage(t *testing.T) {
 	})
 
 	t.Run("ProofAfterEndOfTrace", func(t *testing.T) {
-		provider, generator := setupWithTestData(t, dataDir, prestate)
+		provider, generator := setupWithTestData(t, dataDir, store, prestate)
 		generator.finalState = &mipsevm.State{
 			Memory: &mipsevm.Memory{},
 			Step:   10,
@@ -149,14 +153,14 @@ func TestGetPreimage(t *testing.T) {
 	})
 
 	t.Run("MissingStateData", func(t *testing.T) {
-		provider, generator := setupWithTestData(t, dataDir, prestate)
+		provider, generator := setupWithTestData(t, dataDir, store, prestate)
 		_, _, err := provider.GetPreimage(context.Background(), 1)
 		require.ErrorContains(t, err, "missing state data")
 		require.Empty(t, generator.generated)
 	})
 
 	t.Run("IgnoreUnknownFields", func(t *testing.T) {
-		provider, generator := setupWithTestData(t, dataDir, prestate)
+		provider, generator := setupWithTestData(t, dataDir, store, prestate)
 		value, proof, err := provider.GetPreimage(context.Background(), 2)
 		require.NoError(t, err)
 		expected := common.Hex2Bytes("cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc")
@@ -174,21 +178,21 @@ func TestAbsolutePreState(t *testing.T) {
 	prestate := "state.json"
 
 	t.Run("StateUnavailable", func(t *testing.T) {
-		provider, _ := setupWithTestData(t, "/dir/does/not/exist", prestate)
+		provider, _ := setupWithTestData(t, "/dir/does/not/exist", NewMemProofStore(), prestate)
 		_, err := provider.AbsolutePreState(context.Background())
 		require.ErrorIs(t, err, os.ErrNotExist)
 	})
 
 	t.Run("InvalidStateFile", func(t *testing.T) {
 		setupPreState(t, dataDir, "invalid.json")
-		provider, _ := setupWithTestData(t, dataDir, prestate)
+		provider, _ := setupWithTestData(t, dataDir, NewMemProofStore(), prestate)
 		_, err := provider.AbsolutePreState(context.Background())
 		require.ErrorContains(t, err, "invalid mipsevm state")
 	})
 
 	t.Run("ExpectedAbsolutePreState", func(t *testing.T) {
 		setupPreState(t, dataDir, "state.json")
-		provider, _ := setupWithTestData(t, dataDir, prestate)
+		provider, _ := setupWithTestData(t, dataDir, NewMemProofStore(), prestate)
 		preState, err := provider.AbsolutePreState(context.Background())
 		require.NoError(t, err)
 		state := mipsevm.State{
@@ -209,6 +213,126 @@ func TestAbsolutePreState(t *testing.T) {
 	})
 }
 
+func TestReadOnly_NeverGeneratesProofs(t *testing.T) {
+	dataDir, store, prestate := setupTestData(t)
+
+	t.Run("MissingProofReturnsErrProofNotAvailable", func(t *testing.T) {
+		provider, generator := setupWithTestData(t, dataDir, store, prestate, WithReadOnly(true))
+		_, err := provider.Get(context.Background(), 7000)
+		require.ErrorIs(t, err, ErrProofNotAvailable)
+		require.Empty(t, generator.generated, "must not invoke the generator in read-only mode")
+	})
+
+	t.Run("ExistingProofIsStillServed", func(t *testing.T) {
+		provider, generator := setupWithTestData(t, dataDir, store, prestate, WithReadOnly(true))
+		value, err := provider.Get(context.Background(), 0)
+		require.NoError(t, err)
+		require.Equal(t, common.HexToHash("0x45fd9aa59768331c726e719e76aa343e73123af888804604785ae19506e65e87"), value)
+		require.Empty(t, generator.generated)
+	})
+
+	t.Run("FinalStateAlreadyAvailableAnswersPastEndOfTrace", func(t *testing.T) {
+		// A separate builder process may have already populated the final state (and the proof for
+		// the step that produced it) into the store before this read-only provider was started.
+		dataDir, store, prestate := setupTestData(t)
+		finalState := &mipsevm.State{Memory: &mipsevm.Memory{}, Step: 10, Exited: true}
+		require.NoError(t, store.PutFinalState(finalState))
+		require.NoError(t, store.PutProof(9, &proofData{
+			ClaimValue:   common.Hash{0xaa}.Bytes(),
+			StateData:    []byte{0xbb},
+			ProofData:    []byte{0xcc},
+			OracleKey:    common.Hash{0xdd}.Bytes(),
+			OracleValue:  []byte{0xee},
+			OracleOffset: 10,
+		}))
+		provider, generator := setupWithTestData(t, dataDir, store, prestate, WithReadOnly(true))
+
+		value, err := provider.Get(context.Background(), 7000)
+		require.NoError(t, err)
+		require.Equal(t, crypto.Keccak256Hash(finalState.EncodeWitness()), value)
+
+		oracleData, err := provider.GetOracleData(context.Background(), 7000)
+		require.NoError(t, err)
+		require.False(t, oracleData.IsLocal)
+		require.Equal(t, common.Hash{0xdd}.Bytes(), oracleData.OracleKey)
+
+		require.Empty(t, generator.generated, "must not invoke the generator in read-only mode")
+	})
+}
+
+func TestClose(t *testing.T) {
+	t.Run("ClosesCloseableStore", func(t *testing.T) {
+		dataDir, _, prestate := setupTestData(t)
+		store, err := NewLevelDBProofStore(filepath.Join(dataDir, "proofs-db"))
+		require.NoError(t, err)
+		provider, _ := setupWithTestData(t, dataDir, store, prestate)
+
+		require.NoError(t, provider.Close())
+		// A closed leveldb handle rejects further use.
+		_, err = store.LoadProof(0)
+		require.Error(t, err)
+	})
+
+	t.Run("NoopForStoreWithoutClose", func(t *testing.T) {
+		dataDir, store, prestate := setupTestData(t)
+		provider, _ := setupWithTestData(t, dataDir, store, prestate)
+		require.NoError(t, provider.Close())
+	})
+}
+
+func TestConcurrentRequests_DeduplicateGeneration(t *testing.T) {
+	dataDir, store, prestate := setupTestData(t)
+
+	runConcurrently := func(t *testing.T, call func(provider *CannonTraceProvider, i uint64) error, wantGenerated []int) {
+		provider, generator := setupWithTestData(t, dataDir, store, prestate)
+		generator.finalState = &mipsevm.State{Memory: &mipsevm.Memory{}, Step: 10, Exited: true}
+		generator.proof = &proofData{
+			ClaimValue:  common.Hash{0xaa}.Bytes(),
+			StateData:   []byte{0xbb},
+			ProofData:   []byte{0xcc},
+			OracleKey:   common.Hash{0xdd}.Bytes(),
+			OracleValue: []byte{0xdd},
+		}
+		generator.delay = 20 * time.Millisecond
+
+		const concurrency = 10
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(concurrency)
+		for i := 0; i < concurrency; i++ {
+			go func() {
+				defer wg.Done()
+				<-start
+				require.NoError(t, call(provider, 7000))
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		require.ElementsMatch(t, wantGenerated, generator.generated,
+			"concurrent requests for the same step should coalesce into a single generation per step")
+	}
+
+	t.Run("Get", func(t *testing.T) {
+		// Get only needs the claim value, which comes straight from the final state - it never
+		// needs to fall back to the proof for the last real step.
+		runConcurrently(t, func(provider *CannonTraceProvider, i uint64) error {
+			_, err := provider.Get(context.Background(), i)
+			return err
+		}, []int{7000})
+	})
+
+	t.Run("GetOracleData", func(t *testing.T) {
+		// GetOracleData additionally regenerates the proof for the last real step (9) to recover
+		// the oracle data that isn't present in the final state, but concurrent callers still only
+		// trigger one generation per step.
+		runConcurrently(t, func(provider *CannonTraceProvider, i uint64) error {
+			_, err := provider.GetOracleData(context.Background(), i)
+			return err
+		}, []int{7000, 9})
+	})
+}
+
 func setupPreState(t *testing.T, dataDir string, filename string) {
 	srcDir := filepath.Join("test_data")
 	path := filepath.Join(srcDir, filename)
@@ -218,39 +342,57 @@ func setupPreState(t *testing.T, dataDir string, filename string) {
 	require.NoErrorf(t, err, "writing %v", path)
 }
 
-func setupTestData(t *testing.T) (string, string) {
+// setupTestData loads the proof fixtures straight into a MemProofStore, rather than writing them
+// out to dataDir, so the provider never has to touch disk to serve them. dataDir is still
+// returned: it's where the stubGenerator writes newly generated proofs for the provider to pick
+// up and ingest into the store.
+func setupTestData(t *testing.T) (string, *MemProofStore, string) {
 	srcDir := filepath.Join("test_data", "proofs")
 	entries, err := testData.ReadDir(srcDir)
 	require.NoError(t, err)
 	dataDir := t.TempDir()
-	require.NoError(t, os.Mkdir(filepath.Join(dataDir, proofsDir), 0o777))
+	store := NewMemProofStore()
 	for _, entry := range entries {
 		path := filepath.Join(srcDir, entry.Name())
 		file, err := testData.ReadFile(path)
 		require.NoErrorf(t, err, "reading %v", path)
-		err = os.WriteFile(filepath.Join(dataDir, proofsDir, entry.Name()), file, 0o644)
-		require.NoErrorf(t, err, "writing %v", path)
+		var proof proofData
+		require.NoErrorf(t, json.Unmarshal(file, &proof), "parsing %v", path)
+		step, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), ".json"), 10, 64)
+		require.NoErrorf(t, err, "parsing step from %v", entry.Name())
+		require.NoError(t, store.PutProof(step, &proof))
 	}
-	return dataDir, "state.json"
+	return dataDir, store, "state.json"
 }
 
-func setupWithTestData(t *testing.T, dataDir string, prestate string) (*CannonTraceProvider, *stubGenerator) {
+func setupWithTestData(t *testing.T, dataDir string, store ProofStore, prestate string, opts ...TraceProviderOpt) (*CannonTraceProvider, *stubGenerator) {
 	generator := &stubGenerator{}
-	return &CannonTraceProvider{
+	provider := &CannonTraceProvider{
 		logger:    testlog.Logger(t, log.LvlInfo),
 		dir:       dataDir,
 		generator: generator,
+		store:     store,
 		prestate:  prestate,
-	}, generator
+		metrics:   NoopCachingMetrics{},
+	}
+	for _, opt := range opts {
+		opt(provider)
+	}
+	provider.cache = newProofCache(provider.metrics, provider.cacheSize)
+	return provider, generator
 }
 
 type stubGenerator struct {
 	generated  []int // Using int makes assertions easier
 	finalState *mipsevm.State
 	proof      *proofData
+	delay      time.Duration
 }
 
 func (e *stubGenerator) GenerateProof(ctx context.Context, dir string, i uint64) error {
+	if e.delay > 0 {
+		time.Sleep(e.delay)
+	}
 	e.generated = append(e.generated, int(i))
 	if e.finalState != nil && e.finalState.Step <= i {
 		// Requesting a trace index past the end of the trace
@@ -262,6 +404,9 @@ func (e *stubGenerator) GenerateProof(ctx context.Context, dir string, i uint64)
 	}
 	if e.proof != nil {
 		proofFile := filepath.Join(dir, proofsDir, fmt.Sprintf("%d.json", i))
+		if err := os.MkdirAll(filepath.Join(dir, proofsDir), 0o777); err != nil {
+			return err
+		}
 		data, err := json.Marshal(e.proof)
 		if err != nil {
 			return err